@@ -5,12 +5,10 @@ package azure
 import (
 	"errors"
 	"fmt"
-	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
 	"net/http"
 	"time"
 
 	"github.com/Azure/azure-sdk-for-go/sdk/azcore/cloud"
-	"github.com/Azure/azure-sdk-for-go/sdk/azidentity"
 	"github.com/go-acme/lego/v4/challenge"
 	"github.com/go-acme/lego/v4/platform/config/env"
 )
@@ -32,6 +30,12 @@ const (
 	EnvZoneName    = envNamespace + "ZONE_NAME"
 	EnvPrivateZone = envNamespace + "PRIVATE_ZONE"
 
+	EnvMetadataEndpoint = envNamespace + "METADATA_ENDPOINT"
+
+	EnvUseWorkloadIdentity = envNamespace + "USE_WORKLOAD_IDENTITY"
+	EnvFederatedTokenFile  = envNamespace + "FEDERATED_TOKEN_FILE"
+	EnvAuthorityHost       = envNamespace + "AUTHORITY_HOST"
+
 	EnvTTL                = envNamespace + "TTL"
 	EnvPropagationTimeout = envNamespace + "PROPAGATION_TIMEOUT"
 	EnvPollingInterval    = envNamespace + "POLLING_INTERVAL"
@@ -48,8 +52,24 @@ type Config struct {
 	ResourceGroup  string
 	PrivateZone    bool
 
+	// ZoneToResourceGroup overrides, per zone name (without trailing dot), which
+	// subscription/resource group/zone type to use instead of the fields above.
+	// Zones absent from this map use SubscriptionID/ResourceGroup/PrivateZone above when
+	// both SubscriptionID and ResourceGroup are set, and are otherwise discovered via an
+	// Azure Resource Graph query.
+	ZoneToResourceGroup map[string]ZoneRef
+
+	// MetadataEndpoint is only used to probe whether the instance metadata service is reachable,
+	// to decide whether to use a managed identity credential; it does not redirect where that
+	// credential fetches tokens from.
 	MetadataEndpoint string
 
+	// UseWorkloadIdentity forces the use of an AKS workload identity (federated OIDC) credential.
+	// It is otherwise enabled automatically when AZURE_FEDERATED_TOKEN_FILE and AZURE_AUTHORITY_HOST are set.
+	UseWorkloadIdentity bool
+	FederatedTokenFile  string
+	AuthorityHost       string
+
 	CloudConfig cloud.Configuration
 
 	PropagationTimeout time.Duration
@@ -65,6 +85,7 @@ func NewDefaultConfig() *Config {
 		PropagationTimeout: env.GetOrDefaultSecond(EnvPropagationTimeout, 2*time.Minute),
 		PollingInterval:    env.GetOrDefaultSecond(EnvPollingInterval, 2*time.Second),
 		CloudConfig:        cloud.AzurePublic,
+		MetadataEndpoint:   env.GetOrDefaultString(EnvMetadataEndpoint, defaultMetadataEndpoint),
 	}
 }
 
@@ -77,6 +98,8 @@ type DNSProvider struct {
 // Credentials can be passed in the environment variables:
 // AZURE_ENVIRONMENT, AZURE_CLIENT_ID, AZURE_CLIENT_SECRET,
 // AZURE_SUBSCRIPTION_ID, AZURE_TENANT_ID, AZURE_RESOURCE_GROUP
+// Running in AKS with Azure AD Workload Identity is detected automatically from
+// AZURE_FEDERATED_TOKEN_FILE/AZURE_AUTHORITY_HOST (or forced with AZURE_USE_WORKLOAD_IDENTITY).
 // If the credentials are _not_ set via the environment,
 // then it will attempt to get a bearer token via the instance metadata service.
 // see: https://github.com/Azure/go-autorest/blob/v10.14.0/autorest/azure/auth/auth.go#L38-L42
@@ -112,6 +135,14 @@ func NewDNSProvider() (*DNSProvider, error) {
 	config.TenantID = env.GetOrFile(EnvTenantID)
 	config.PrivateZone = env.GetOrDefaultBool(EnvPrivateZone, false)
 
+	if endpoint := env.GetOrFile(EnvMetadataEndpoint); endpoint != "" {
+		config.MetadataEndpoint = endpoint
+	}
+
+	config.UseWorkloadIdentity = env.GetOrDefaultBool(EnvUseWorkloadIdentity, false)
+	config.FederatedTokenFile = env.GetOrFile(EnvFederatedTokenFile)
+	config.AuthorityHost = env.GetOrFile(EnvAuthorityHost)
+
 	return NewDNSProviderConfig(config)
 }
 
@@ -125,22 +156,22 @@ func NewDNSProviderConfig(config *Config) (*DNSProvider, error) {
 		config.HTTPClient = http.DefaultClient
 	}
 
-	if config.SubscriptionID == "" {
-		return nil, errors.New("azure: SubscriptionID is missing")
-	}
+	// SubscriptionID/ResourceGroup are required unless ZoneToResourceGroup is non-empty: zones it
+	// lists need no default, and any other zone is then located via Resource Graph discovery (which
+	// searches every subscription the credential can see when SubscriptionID is empty).
+	if len(config.ZoneToResourceGroup) == 0 {
+		if config.SubscriptionID == "" {
+			return nil, errors.New("azure: SubscriptionID is missing")
+		}
 
-	if config.ResourceGroup == "" {
-		return nil, errors.New("azure: ResourceGroup is missing")
+		if config.ResourceGroup == "" {
+			return nil, errors.New("azure: ResourceGroup is missing")
+		}
 	}
 
-	clientOpts := azcore.ClientOptions{Cloud: cloud.AzureChina}
-	cred, err := azidentity.NewEnvironmentCredential(&azidentity.EnvironmentCredentialOptions{ClientOptions: clientOpts})
-	// cred, err := azidentity.NewClientSecretCredential(
-	// 	config.TenantID, config.ClientID, config.ClientSecret, &azidentity.ClientSecretCredentialOptions{ClientOptions: clientOpts},
-	// )
-
+	cred, err := newCredential(config)
 	if err != nil {
-		return nil, fmt.Errorf("azidentity.NewEnvironmentCredential failed: %w", err)
+		return nil, fmt.Errorf("azure: %w", err)
 	}
 
 	return &DNSProvider{provider: &dnsProvider{config: config, authorizer: cred}}, nil
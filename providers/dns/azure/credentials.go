@@ -0,0 +1,119 @@
+package azure
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/cloud"
+	"github.com/Azure/azure-sdk-for-go/sdk/azidentity"
+)
+
+// metadataProbeTimeout is the time allowed to decide whether the instance
+// metadata service is reachable before falling back to other credential types.
+const metadataProbeTimeout = 500 * time.Millisecond
+
+// newCredential selects the azidentity credential to use based on what the
+// user supplied in config:
+//   - an explicit ClientID/ClientSecret/TenantID triple uses a client secret credential.
+//   - config.UseWorkloadIdentity, or the presence of the AZURE_FEDERATED_TOKEN_FILE/
+//     AZURE_AUTHORITY_HOST pair the AKS workload-identity webhook injects into the pod,
+//     uses a workload identity (federated OIDC) credential. A non-empty config.AuthorityHost
+//     overrides the AAD authority the token is requested from (azidentity itself only ever reads
+//     AZURE_AUTHORITY_HOST from the environment, so this is what makes a programmatically-set
+//     Config.AuthorityHost take effect without also exporting the env var).
+//   - a reachable instance metadata service (config.MetadataEndpoint, or the standard
+//     169.254.169.254 IMDS address when unset) picks a managed identity credential. Note that
+//     config.MetadataEndpoint only gates *this reachability probe*: azidentity.
+//     ManagedIdentityCredentialOptions has no field to redirect the actual token request
+//     elsewhere, so a non-default config.MetadataEndpoint is only useful to point the probe at
+//     a reachable stand-in for IMDS (e.g. in tests); it does not change where the credential
+//     itself fetches tokens from.
+//   - everything else falls back to azidentity.NewDefaultAzureCredential,
+//     which itself walks EnvironmentCredential, WorkloadIdentityCredential,
+//     ManagedIdentityCredential, and the Azure CLI.
+func newCredential(config *Config) (azcore.TokenCredential, error) {
+	clientOpts := azcore.ClientOptions{Cloud: config.CloudConfig}
+
+	switch {
+	case config.ClientID != "" && config.ClientSecret != "" && config.TenantID != "":
+		cred, err := azidentity.NewClientSecretCredential(config.TenantID, config.ClientID, config.ClientSecret,
+			&azidentity.ClientSecretCredentialOptions{ClientOptions: clientOpts})
+		if err != nil {
+			return nil, fmt.Errorf("azidentity.NewClientSecretCredential failed: %w", err)
+		}
+		return cred, nil
+
+	case config.UseWorkloadIdentity || (config.FederatedTokenFile != "" && config.AuthorityHost != ""):
+		wiOpts := clientOpts
+		if config.AuthorityHost != "" {
+			wiOpts.Cloud = cloud.Configuration{ActiveDirectoryAuthorityHost: config.AuthorityHost, Services: config.CloudConfig.Services}
+		}
+
+		cred, err := azidentity.NewWorkloadIdentityCredential(&azidentity.WorkloadIdentityCredentialOptions{
+			ClientOptions: wiOpts,
+			ClientID:      config.ClientID,
+			TenantID:      config.TenantID,
+			TokenFilePath: config.FederatedTokenFile,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("azidentity.NewWorkloadIdentityCredential failed: %w", err)
+		}
+		return cred, nil
+
+	case metadataServiceAvailable(config.MetadataEndpoint, config.HTTPClient):
+		cred, err := azidentity.NewManagedIdentityCredential(&azidentity.ManagedIdentityCredentialOptions{ClientOptions: clientOpts})
+		if err != nil {
+			return nil, fmt.Errorf("azidentity.NewManagedIdentityCredential failed: %w", err)
+		}
+		return cred, nil
+
+	default:
+		cred, err := azidentity.NewDefaultAzureCredential(&azidentity.DefaultAzureCredentialOptions{ClientOptions: clientOpts})
+		if err != nil {
+			return nil, fmt.Errorf("azidentity.NewDefaultAzureCredential failed: %w", err)
+		}
+		return cred, nil
+	}
+}
+
+// metadataServiceAvailable reports whether the Azure instance metadata service
+// answers at endpoint, which is how the official SDKs detect that code is
+// running on an Azure VM (or anything else fronted by IMDS, e.g. a container
+// on an AKS node that isn't using workload identity).
+//
+// This is a live, synchronous HTTP call (capped at metadataProbeTimeout), made once per
+// NewDNSProviderConfig, and only when neither the client-secret nor the workload-identity
+// branch above already matched. On a non-Azure host this still costs up to metadataProbeTimeout
+// (today 500ms) of added latency at provider construction before falling back to
+// NewDefaultAzureCredential.
+func metadataServiceAvailable(endpoint string, httpClient *http.Client) bool {
+	if endpoint == "" {
+		endpoint = defaultMetadataEndpoint
+	}
+
+	client := httpClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	req, err := http.NewRequest(http.MethodGet, endpoint+"/metadata/instance?api-version=2019-06-01", nil)
+	if err != nil {
+		return false
+	}
+
+	req.Header.Set("Metadata", "true")
+
+	ctx, cancel := context.WithTimeout(context.Background(), metadataProbeTimeout)
+	defer cancel()
+
+	resp, err := client.Do(req.WithContext(ctx))
+	if err != nil {
+		return false
+	}
+	defer resp.Body.Close()
+
+	return resp.StatusCode == http.StatusOK
+}
@@ -0,0 +1,93 @@
+package azure
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/cloud"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	"github.com/go-acme/lego/v4/challenge/dns01"
+)
+
+// dns01TestRecord returns the fqdn/value dns01.GetRecord would compute for domain/keyAuth, so
+// tests can pre-seed a fake record set Get response with the exact value Present/CleanUp will
+// use, without duplicating lego's key authorization hashing here.
+func dns01TestRecord(domain, keyAuth string) (fqdn, value string) {
+	return dns01.GetRecord(domain, keyAuth)
+}
+
+// fakeCredential is an azcore.TokenCredential that never touches the network, so tests can drive
+// armdns/armprivatedns clients entirely through a fakeTransport.
+type fakeCredential struct{}
+
+func (fakeCredential) GetToken(context.Context, policy.TokenRequestOptions) (azcore.AccessToken, error) {
+	return azcore.AccessToken{Token: "fake-token", ExpiresOn: time.Now().Add(time.Hour)}, nil
+}
+
+// fakeStep is one canned HTTP response a fakeTransport replays, in order.
+type fakeStep struct {
+	wantMethod string
+	// wantHeader, when set, lists request headers the step's request must carry with these values.
+	wantHeader map[string]string
+	status     int
+	body       string
+}
+
+// fakeTransport is an http.RoundTripper that replays steps in order and fails the test if a
+// request's method or wanted headers don't match the expected step, or if more requests arrive
+// than expected.
+type fakeTransport struct {
+	t     *testing.T
+	steps []fakeStep
+	calls int
+}
+
+func (f *fakeTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	f.t.Helper()
+
+	if f.calls >= len(f.steps) {
+		f.t.Fatalf("unexpected extra request #%d: %s %s", f.calls+1, req.Method, req.URL)
+	}
+
+	step := f.steps[f.calls]
+	f.calls++
+
+	if req.Method != step.wantMethod {
+		f.t.Errorf("request #%d: got method %s, want %s (%s)", f.calls, req.Method, step.wantMethod, req.URL)
+	}
+
+	for name, want := range step.wantHeader {
+		if got := req.Header.Get(name); got != want {
+			f.t.Errorf("request #%d: got header %s=%q, want %q (%s)", f.calls, name, got, want, req.URL)
+		}
+	}
+
+	return &http.Response{
+		StatusCode: step.status,
+		Status:     http.StatusText(step.status),
+		Body:       io.NopCloser(strings.NewReader(step.body)),
+		Header:     http.Header{"Content-Type": []string{"application/json"}},
+		Request:    req,
+	}, nil
+}
+
+// newFakeProvider builds a dnsProvider wired to a credential and HTTP transport that never touch
+// the network, replaying steps as the canned response to each successive outgoing request.
+func newFakeProvider(t *testing.T, config *Config, steps ...fakeStep) *dnsProvider {
+	t.Helper()
+
+	config.HTTPClient = &http.Client{Transport: &fakeTransport{t: t, steps: steps}}
+	if (config.CloudConfig == cloud.Configuration{}) {
+		config.CloudConfig = cloud.AzurePublic
+	}
+	if config.TTL == 0 {
+		config.TTL = 60
+	}
+
+	return &dnsProvider{config: config, authorizer: fakeCredential{}}
+}
@@ -0,0 +1,165 @@
+package azure
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/privatedns/armprivatedns"
+	"github.com/go-acme/lego/v4/challenge/dns01"
+	"github.com/go-acme/lego/v4/providers/dns/azure/to"
+)
+
+// presentPrivate creates a TXT record in an Azure Private DNS zone to fulfill the dns-01 challenge.
+func (d *dnsProvider) presentPrivate(ctx context.Context, fqdn, value, zone string, ref ZoneRef) error {
+	rsc, err := armprivatedns.NewRecordSetsClient(ref.SubscriptionID, d.authorizer, d.clientOptions())
+	if err != nil {
+		return fmt.Errorf("azure: %w", err)
+	}
+
+	subDomain, err := dns01.ExtractSubDomain(fqdn, zone)
+	if err != nil {
+		return fmt.Errorf("azure: %w", err)
+	}
+
+	err = retryRecordSetUpdate(func() error {
+		return d.mergeTXTRecordPrivate(ctx, rsc, ref, zone, subDomain, value)
+	})
+	if err != nil {
+		return fmt.Errorf("azure: %w", err)
+	}
+	return nil
+}
+
+// mergeTXTRecordPrivate adds value to the TXT record set at subDomain, using an IfMatch ETag so
+// concurrent writers (e.g. a wildcard and an apex issuance running in parallel) can't clobber
+// each other's values; the caller is expected to retry on a 412 Precondition Failed.
+func (d *dnsProvider) mergeTXTRecordPrivate(ctx context.Context, rsc *armprivatedns.RecordSetsClient, ref ZoneRef, zone, subDomain, value string) error {
+	// Get existing record set
+	rset, err := rsc.Get(ctx, ref.ResourceGroup, zone, armprivatedns.RecordTypeTXT, subDomain, nil)
+
+	// opts guards the write below the same way mergeTXTRecordPublic does: IfMatch when the
+	// record set already exists, IfNoneMatch: "*" when it doesn't, so a concurrent first
+	// creation loses with a 412 instead of silently overwriting this value.
+	opts := &armprivatedns.RecordSetsClientCreateOrUpdateOptions{}
+	if err != nil {
+		var detailed = &azcore.ResponseError{}
+		if !errors.As(err, &detailed) || detailed.StatusCode != http.StatusNotFound {
+			return err
+		}
+		opts.IfNoneMatch = to.StringPtr("*")
+	} else {
+		opts.IfMatch = rset.Etag
+	}
+
+	// Construct unique TXT records using map
+	uniqRecords := map[string]struct{}{value: {}}
+	if rset.Properties != nil && rset.Properties.TxtRecords != nil {
+		for _, txtRecord := range rset.Properties.TxtRecords {
+			// Assume Value doesn't contain multiple strings
+			if len(txtRecord.Value) > 0 && txtRecord.Value[0] != nil {
+				uniqRecords[*txtRecord.Value[0]] = struct{}{}
+			}
+		}
+	}
+
+	var txtRecords []*armprivatedns.TxtRecord
+	for txt := range uniqRecords {
+		txtRecords = append(txtRecords, &armprivatedns.TxtRecord{Value: []*string{&txt}})
+	}
+
+	rec := armprivatedns.RecordSet{
+		Name: &subDomain,
+		Properties: &armprivatedns.RecordSetProperties{
+			TTL:        to.Int64Ptr(int64(d.config.TTL)),
+			TxtRecords: txtRecords,
+		},
+	}
+
+	_, err = rsc.CreateOrUpdate(ctx, ref.ResourceGroup, zone, armprivatedns.RecordTypeTXT, subDomain, rec, opts)
+	return err
+}
+
+// cleanUpPrivate removes value from the TXT record set matching the specified parameters in an
+// Azure Private DNS zone, deleting the record set entirely once it is left empty.
+func (d *dnsProvider) cleanUpPrivate(ctx context.Context, fqdn, value, zone string, ref ZoneRef) error {
+	subDomain, err := dns01.ExtractSubDomain(fqdn, zone)
+	if err != nil {
+		return fmt.Errorf("azure: %w", err)
+	}
+
+	rsc, err := armprivatedns.NewRecordSetsClient(ref.SubscriptionID, d.authorizer, d.clientOptions())
+	if err != nil {
+		return fmt.Errorf("azure: %w", err)
+	}
+
+	err = retryRecordSetUpdate(func() error {
+		return d.trimTXTRecordPrivate(ctx, rsc, ref, zone, subDomain, value)
+	})
+	if err != nil {
+		return fmt.Errorf("azure: %w", err)
+	}
+	return nil
+}
+
+// trimTXTRecordPrivate removes value from the TXT record set at subDomain, using an IfMatch
+// ETag so concurrent writers can't clobber each other's values; the caller is expected to retry
+// on a 412 Precondition Failed. The record set itself is deleted, rather than updated, once
+// trimming value leaves it empty.
+func (d *dnsProvider) trimTXTRecordPrivate(ctx context.Context, rsc *armprivatedns.RecordSetsClient, ref ZoneRef, zone, subDomain, value string) error {
+	rset, err := rsc.Get(ctx, ref.ResourceGroup, zone, armprivatedns.RecordTypeTXT, subDomain, nil)
+	if err != nil {
+		var detailed = &azcore.ResponseError{}
+		if errors.As(err, &detailed) && detailed.StatusCode == http.StatusNotFound {
+			return nil
+		}
+		return err
+	}
+
+	var txtRecords []*armprivatedns.TxtRecord
+	if rset.Properties != nil {
+		for _, txtRecord := range rset.Properties.TxtRecords {
+			// Assume Value doesn't contain multiple strings
+			if len(txtRecord.Value) > 0 && txtRecord.Value[0] != nil && *txtRecord.Value[0] == value {
+				continue
+			}
+			txtRecords = append(txtRecords, txtRecord)
+		}
+	}
+
+	if len(txtRecords) == 0 {
+		_, err := rsc.Delete(ctx, ref.ResourceGroup, zone, armprivatedns.RecordTypeTXT, subDomain,
+			&armprivatedns.RecordSetsClientDeleteOptions{IfMatch: rset.Etag})
+		return err
+	}
+
+	rec := armprivatedns.RecordSet{
+		Name: &subDomain,
+		Properties: &armprivatedns.RecordSetProperties{
+			TTL:        rset.Properties.TTL,
+			TxtRecords: txtRecords,
+		},
+	}
+
+	_, err = rsc.CreateOrUpdate(ctx, ref.ResourceGroup, zone, armprivatedns.RecordTypeTXT, subDomain, rec,
+		&armprivatedns.RecordSetsClientCreateOrUpdateOptions{IfMatch: rset.Etag})
+	return err
+}
+
+// getHostedZoneIDPrivate checks that azure has a private zone named zone in ref, returning its canonical name.
+func (d *dnsProvider) getHostedZoneIDPrivate(ctx context.Context, zone string, ref ZoneRef) (string, error) {
+	dc, err := armprivatedns.NewPrivateZonesClient(ref.SubscriptionID, d.authorizer, d.clientOptions())
+	if err != nil {
+		return "", err
+	}
+
+	zoneResult, err := dc.Get(ctx, ref.ResourceGroup, zone, nil)
+	if err != nil {
+		return "", err
+	}
+
+	// zoneResult.Name shouldn't have a trailing dot(.)
+	return to.String(zoneResult.Name), nil
+}
@@ -0,0 +1,70 @@
+package azure
+
+import (
+	"net/http"
+	"testing"
+)
+
+func privateTestConfig() *Config {
+	return &Config{
+		PrivateZone: true,
+		ZoneToResourceGroup: map[string]ZoneRef{
+			"example.com": {SubscriptionID: "sub1", ResourceGroup: "rg1", PrivateZone: true},
+		},
+	}
+}
+
+func TestPresentPrivate(t *testing.T) {
+	t.Setenv(EnvZoneName, "example.com")
+
+	d := newFakeProvider(t, privateTestConfig(),
+		fakeStep{wantMethod: http.MethodGet, status: http.StatusOK, body: `{"name":"example.com"}`},
+		fakeStep{wantMethod: http.MethodGet, status: http.StatusNotFound, body: `{"error":{"code":"NotFound","message":"not found"}}`},
+		fakeStep{wantMethod: http.MethodPut, status: http.StatusOK, body: `{"name":"_acme-challenge","etag":"etag1"}`},
+	)
+
+	err := d.Present("example.com", "token", "keyAuth")
+	if err != nil {
+		t.Fatalf("Present: unexpected error: %v", err)
+	}
+}
+
+func TestCleanUpPrivate_TrimsRemainingValue(t *testing.T) {
+	t.Setenv(EnvZoneName, "example.com")
+
+	_, value := dns01TestRecord("example.com", "keyAuth")
+
+	d := newFakeProvider(t, privateTestConfig(),
+		fakeStep{wantMethod: http.MethodGet, status: http.StatusOK, body: `{"name":"example.com"}`},
+		fakeStep{
+			wantMethod: http.MethodGet,
+			status:     http.StatusOK,
+			body:       `{"name":"_acme-challenge","etag":"etag1","properties":{"ttl":60,"txtRecords":[{"value":["` + value + `"]},{"value":["other-value"]}]}}`,
+		},
+		fakeStep{wantMethod: http.MethodPut, status: http.StatusOK, body: `{"name":"_acme-challenge","etag":"etag2"}`},
+	)
+
+	if err := d.CleanUp("example.com", "token", "keyAuth"); err != nil {
+		t.Fatalf("CleanUp: unexpected error: %v", err)
+	}
+}
+
+func TestCleanUpPrivate_DeletesWhenEmpty(t *testing.T) {
+	t.Setenv(EnvZoneName, "example.com")
+
+	_, value := dns01TestRecord("example.com", "keyAuth")
+
+	d := newFakeProvider(t, privateTestConfig(),
+		fakeStep{wantMethod: http.MethodGet, status: http.StatusOK, body: `{"name":"example.com"}`},
+		fakeStep{
+			wantMethod: http.MethodGet,
+			status:     http.StatusOK,
+			body:       `{"name":"_acme-challenge","etag":"etag1","properties":{"ttl":60,"txtRecords":[{"value":["` + value + `"]}]}}`,
+		},
+		fakeStep{wantMethod: http.MethodDelete, status: http.StatusOK, body: ``},
+	)
+
+	if err := d.CleanUp("example.com", "token", "keyAuth"); err != nil {
+		t.Fatalf("CleanUp: unexpected error: %v", err)
+	}
+}
@@ -4,21 +4,32 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
-	"github.com/Azure/azure-sdk-for-go/sdk/azidentity"
-	"github.com/go-acme/lego/v4/providers/dns/azure/to"
 	"net/http"
 	"time"
 
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/arm"
 	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/dns/armdns"
 	"github.com/go-acme/lego/v4/challenge/dns01"
-	"github.com/go-acme/lego/v4/platform/config/env"
+	"github.com/go-acme/lego/v4/providers/dns/azure/to"
 )
 
 // dnsProvider implements the challenge.Provider interface for Azure DNS.
 type dnsProvider struct {
 	config     *Config
-	authorizer *azidentity.EnvironmentCredential
+	authorizer azcore.TokenCredential
+}
+
+// clientOptions returns the arm.ClientOptions to use for every armdns/armprivatedns/
+// armresourcegraph client, so they all target the same Azure cloud as the credential and go
+// through config.HTTPClient (e.g. a fake transport in tests) rather than azcore's own default.
+func (d *dnsProvider) clientOptions() *arm.ClientOptions {
+	return &arm.ClientOptions{
+		ClientOptions: azcore.ClientOptions{
+			Cloud:     d.config.CloudConfig,
+			Transport: d.config.HTTPClient,
+		},
+	}
 }
 
 // Timeout returns the timeout and interval to use when checking for DNS propagation.
@@ -32,12 +43,20 @@ func (d *dnsProvider) Present(domain, token, keyAuth string) error {
 	ctx := context.Background()
 	fqdn, value := dns01.GetRecord(domain, keyAuth)
 
-	zone, err := d.getHostedZoneID(ctx, fqdn)
+	zone, ref, err := d.getHostedZoneID(ctx, fqdn)
 	if err != nil {
 		return fmt.Errorf("azure: %w", err)
 	}
 
-	rsc, err := armdns.NewRecordSetsClient(d.config.SubscriptionID, d.authorizer, nil)
+	if ref.PrivateZone {
+		return d.presentPrivate(ctx, fqdn, value, zone, ref)
+	}
+	return d.presentPublic(ctx, fqdn, value, zone, ref)
+}
+
+// presentPublic creates a TXT record in an Azure (public) DNS zone to fulfill the dns-01 challenge.
+func (d *dnsProvider) presentPublic(ctx context.Context, fqdn, value, zone string, ref ZoneRef) error {
+	rsc, err := armdns.NewRecordSetsClient(ref.SubscriptionID, d.authorizer, d.clientOptions())
 	if err != nil {
 		return fmt.Errorf("azure: %w", err)
 	}
@@ -47,13 +66,36 @@ func (d *dnsProvider) Present(domain, token, keyAuth string) error {
 		return fmt.Errorf("azure: %w", err)
 	}
 
+	err = retryRecordSetUpdate(func() error {
+		return d.mergeTXTRecordPublic(ctx, rsc, ref, zone, subDomain, value)
+	})
+	if err != nil {
+		return fmt.Errorf("azure: %w", err)
+	}
+	return nil
+}
+
+// mergeTXTRecordPublic adds value to the TXT record set at subDomain, using an IfMatch ETag so
+// concurrent writers (e.g. a wildcard and an apex issuance running in parallel) can't clobber
+// each other's values; the caller is expected to retry on a 412 Precondition Failed.
+func (d *dnsProvider) mergeTXTRecordPublic(ctx context.Context, rsc *armdns.RecordSetsClient, ref ZoneRef, zone, subDomain, value string) error {
 	// Get existing record set
-	rset, err := rsc.Get(ctx, d.config.ResourceGroup, zone, subDomain, armdns.RecordTypeTXT, nil)
+	rset, err := rsc.Get(ctx, ref.ResourceGroup, zone, subDomain, armdns.RecordTypeTXT, nil)
+
+	// opts guards the write below with the record set's ETag, so two concurrent issuances for
+	// the same FQDN (e.g. a wildcard and an apex) can't silently clobber each other's value.
+	// When the record set doesn't exist yet there's no ETag to match, so IfNoneMatch: "*" is
+	// used instead: it makes the create fail with 412 if a concurrent writer creates it first,
+	// which retryRecordSetUpdate turns into a re-fetch-and-merge retry instead of a lost write.
+	opts := &armdns.RecordSetsClientCreateOrUpdateOptions{}
 	if err != nil {
 		var detailed = &azcore.ResponseError{}
 		if !errors.As(err, &detailed) || detailed.StatusCode != http.StatusNotFound {
-			return fmt.Errorf("azure: %w", err)
+			return err
 		}
+		opts.IfNoneMatch = to.StringPtr("*")
+	} else {
+		opts.IfMatch = rset.Etag
 	}
 
 	// Construct unique TXT records using map
@@ -80,61 +122,104 @@ func (d *dnsProvider) Present(domain, token, keyAuth string) error {
 		},
 	}
 
-	_, err = rsc.CreateOrUpdate(ctx, d.config.ResourceGroup, zone, subDomain, armdns.RecordTypeTXT, rec, nil)
-	if err != nil {
-		return fmt.Errorf("azure: %w", err)
-	}
-	return nil
+	_, err = rsc.CreateOrUpdate(ctx, ref.ResourceGroup, zone, subDomain, armdns.RecordTypeTXT, rec, opts)
+	return err
 }
 
 // CleanUp removes the TXT record matching the specified parameters.
 func (d *dnsProvider) CleanUp(domain, token, keyAuth string) error {
 	ctx := context.Background()
-	fqdn, _ := dns01.GetRecord(domain, keyAuth)
+	fqdn, value := dns01.GetRecord(domain, keyAuth)
 
-	zone, err := d.getHostedZoneID(ctx, fqdn)
+	zone, ref, err := d.getHostedZoneID(ctx, fqdn)
 	if err != nil {
 		return fmt.Errorf("azure: %w", err)
 	}
 
+	if ref.PrivateZone {
+		return d.cleanUpPrivate(ctx, fqdn, value, zone, ref)
+	}
+	return d.cleanUpPublic(ctx, fqdn, value, zone, ref)
+}
+
+// cleanUpPublic removes value from the TXT record set matching the specified parameters in an
+// Azure (public) DNS zone, deleting the record set entirely once it is left empty.
+func (d *dnsProvider) cleanUpPublic(ctx context.Context, fqdn, value, zone string, ref ZoneRef) error {
 	subDomain, err := dns01.ExtractSubDomain(fqdn, zone)
 	if err != nil {
 		return fmt.Errorf("azure: %w", err)
 	}
 
-	rsc, err := armdns.NewRecordSetsClient(d.config.SubscriptionID, d.authorizer, nil)
+	rsc, err := armdns.NewRecordSetsClient(ref.SubscriptionID, d.authorizer, d.clientOptions())
 	if err != nil {
 		return fmt.Errorf("azure: %w", err)
 	}
 
-	_, err = rsc.Delete(ctx, d.config.ResourceGroup, zone, subDomain, armdns.RecordTypeTXT, nil)
+	err = retryRecordSetUpdate(func() error {
+		return d.trimTXTRecordPublic(ctx, rsc, ref, zone, subDomain, value)
+	})
 	if err != nil {
 		return fmt.Errorf("azure: %w", err)
 	}
 	return nil
 }
 
-// Checks that azure has a zone for this domain name.
-func (d *dnsProvider) getHostedZoneID(ctx context.Context, fqdn string) (string, error) {
-	if zone := env.GetOrFile(EnvZoneName); zone != "" {
-		return zone, nil
+// trimTXTRecordPublic removes value from the TXT record set at subDomain, using an IfMatch ETag
+// so concurrent writers can't clobber each other's values; the caller is expected to retry on a
+// 412 Precondition Failed. The record set itself is deleted, rather than updated, once trimming
+// value leaves it empty.
+func (d *dnsProvider) trimTXTRecordPublic(ctx context.Context, rsc *armdns.RecordSetsClient, ref ZoneRef, zone, subDomain, value string) error {
+	rset, err := rsc.Get(ctx, ref.ResourceGroup, zone, subDomain, armdns.RecordTypeTXT, nil)
+	if err != nil {
+		var detailed = &azcore.ResponseError{}
+		if errors.As(err, &detailed) && detailed.StatusCode == http.StatusNotFound {
+			return nil
+		}
+		return err
 	}
 
-	authZone, err := dns01.FindZoneByFqdn(fqdn)
-	if err != nil {
-		return "", err
+	var txtRecords []*armdns.TxtRecord
+	if rset.Properties != nil {
+		for _, txtRecord := range rset.Properties.TxtRecords {
+			// Assume Value doesn't contain multiple strings
+			if len(txtRecord.Value) > 0 && txtRecord.Value[0] != nil && *txtRecord.Value[0] == value {
+				continue
+			}
+			txtRecords = append(txtRecords, txtRecord)
+		}
 	}
 
-	dc, err := armdns.NewZonesClient(d.config.SubscriptionID, d.authorizer, nil)
+	if len(txtRecords) == 0 {
+		_, err := rsc.Delete(ctx, ref.ResourceGroup, zone, subDomain, armdns.RecordTypeTXT,
+			&armdns.RecordSetsClientDeleteOptions{IfMatch: rset.Etag})
+		return err
+	}
+
+	rec := armdns.RecordSet{
+		Name: &subDomain,
+		Properties: &armdns.RecordSetProperties{
+			TTL:        rset.Properties.TTL,
+			TxtRecords: txtRecords,
+		},
+	}
+
+	_, err = rsc.CreateOrUpdate(ctx, ref.ResourceGroup, zone, subDomain, armdns.RecordTypeTXT, rec,
+		&armdns.RecordSetsClientCreateOrUpdateOptions{IfMatch: rset.Etag})
+	return err
+}
+
+// getHostedZoneIDPublic checks that azure has a (public) zone named zone in ref, returning its canonical name.
+func (d *dnsProvider) getHostedZoneIDPublic(ctx context.Context, zone string, ref ZoneRef) (string, error) {
+	dc, err := armdns.NewZonesClient(ref.SubscriptionID, d.authorizer, d.clientOptions())
 	if err != nil {
 		return "", err
 	}
 
-	zone, err := dc.Get(ctx, d.config.ResourceGroup, dns01.UnFqdn(authZone), nil)
+	zoneResult, err := dc.Get(ctx, ref.ResourceGroup, zone, nil)
 	if err != nil {
 		return "", err
 	}
 
-	// zone.Name shouldn't have a trailing dot(.)
-	return to.String(zone.Name), nil
+	// zoneResult.Name shouldn't have a trailing dot(.)
+	return to.String(zoneResult.Name), nil
 }
@@ -0,0 +1,101 @@
+package azure
+
+import (
+	"net/http"
+	"testing"
+)
+
+func publicTestConfig() *Config {
+	return &Config{
+		ZoneToResourceGroup: map[string]ZoneRef{
+			"example.com": {SubscriptionID: "sub1", ResourceGroup: "rg1"},
+		},
+	}
+}
+
+func TestPresentPublic_GuardsFirstCreationWithIfNoneMatch(t *testing.T) {
+	t.Setenv(EnvZoneName, "example.com")
+
+	d := newFakeProvider(t, publicTestConfig(),
+		fakeStep{wantMethod: http.MethodGet, status: http.StatusOK, body: `{"name":"example.com"}`},
+		fakeStep{wantMethod: http.MethodGet, status: http.StatusNotFound, body: `{"error":{"code":"NotFound","message":"not found"}}`},
+		fakeStep{
+			wantMethod: http.MethodPut,
+			wantHeader: map[string]string{"If-None-Match": "*"},
+			status:     http.StatusOK,
+			body:       `{"name":"_acme-challenge","etag":"etag1"}`,
+		},
+	)
+
+	if err := d.Present("example.com", "token", "keyAuth"); err != nil {
+		t.Fatalf("Present: unexpected error: %v", err)
+	}
+}
+
+func TestCleanUpPublic_TrimsRemainingValue(t *testing.T) {
+	t.Setenv(EnvZoneName, "example.com")
+
+	_, value := dns01TestRecord("example.com", "keyAuth")
+
+	d := newFakeProvider(t, publicTestConfig(),
+		fakeStep{wantMethod: http.MethodGet, status: http.StatusOK, body: `{"name":"example.com"}`},
+		fakeStep{
+			wantMethod: http.MethodGet,
+			status:     http.StatusOK,
+			body:       `{"name":"_acme-challenge","etag":"etag1","properties":{"TTL":60,"txtRecords":[{"value":["` + value + `"]},{"value":["other-value"]}]}}`,
+		},
+		fakeStep{wantMethod: http.MethodPut, status: http.StatusOK, body: `{"name":"_acme-challenge","etag":"etag2"}`},
+	)
+
+	if err := d.CleanUp("example.com", "token", "keyAuth"); err != nil {
+		t.Fatalf("CleanUp: unexpected error: %v", err)
+	}
+}
+
+func TestCleanUpPublic_DeletesWhenEmpty(t *testing.T) {
+	t.Setenv(EnvZoneName, "example.com")
+
+	_, value := dns01TestRecord("example.com", "keyAuth")
+
+	d := newFakeProvider(t, publicTestConfig(),
+		fakeStep{wantMethod: http.MethodGet, status: http.StatusOK, body: `{"name":"example.com"}`},
+		fakeStep{
+			wantMethod: http.MethodGet,
+			status:     http.StatusOK,
+			body:       `{"name":"_acme-challenge","etag":"etag1","properties":{"TTL":60,"txtRecords":[{"value":["` + value + `"]}]}}`,
+		},
+		fakeStep{wantMethod: http.MethodDelete, status: http.StatusOK, body: ``},
+	)
+
+	if err := d.CleanUp("example.com", "token", "keyAuth"); err != nil {
+		t.Fatalf("CleanUp: unexpected error: %v", err)
+	}
+}
+
+// TestPresentPublic_RetriesOn412 exercises retryRecordSetUpdate end-to-end: the first
+// Get+CreateOrUpdate attempt loses the ETag race (a concurrent writer got there first, so the
+// CreateOrUpdate comes back 412), and the second attempt (a fresh Get picking up the concurrent
+// writer's value, merged with ours) succeeds.
+func TestPresentPublic_RetriesOn412(t *testing.T) {
+	t.Setenv(EnvZoneName, "example.com")
+
+	d := newFakeProvider(t, publicTestConfig(),
+		fakeStep{wantMethod: http.MethodGet, status: http.StatusOK, body: `{"name":"example.com"}`},
+		// First attempt: record set doesn't exist yet from our point of view...
+		fakeStep{wantMethod: http.MethodGet, status: http.StatusNotFound, body: `{"error":{"code":"NotFound","message":"not found"}}`},
+		// ...but a concurrent writer created it first, so our IfNoneMatch create loses.
+		fakeStep{wantMethod: http.MethodPut, status: http.StatusPreconditionFailed, body: `{"error":{"code":"PreconditionFailed","message":"etag mismatch"}}`},
+		// Second attempt: re-fetch sees the concurrent writer's record set...
+		fakeStep{
+			wantMethod: http.MethodGet,
+			status:     http.StatusOK,
+			body:       `{"name":"_acme-challenge","etag":"etag1","properties":{"TTL":60,"txtRecords":[{"value":["concurrent-value"]}]}}`,
+		},
+		// ...and merges ours in, guarded by that ETag, which now succeeds.
+		fakeStep{wantMethod: http.MethodPut, status: http.StatusOK, body: `{"name":"_acme-challenge","etag":"etag2"}`},
+	)
+
+	if err := d.Present("example.com", "token", "keyAuth"); err != nil {
+		t.Fatalf("Present: unexpected error: %v", err)
+	}
+}
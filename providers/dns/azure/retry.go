@@ -0,0 +1,62 @@
+package azure
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+)
+
+// maxRecordSetRetries bounds the number of Get-merge-CreateOrUpdate attempts made against a
+// single TXT record set before giving up. Concurrent lego processes (e.g. a wildcard and an
+// apex issuance running in parallel) race on the same record set, so the ETag-guarded write
+// is expected to occasionally lose and need a retry.
+const maxRecordSetRetries = 5
+
+// recordSetBackoff returns the capped exponential backoff to sleep before attempt number attempt
+// (0-indexed) of a record set update; retryRecordSetUpdate calls it only for attempt >= 1, so the
+// first retry sleeps 400ms.
+func recordSetBackoff(attempt int) time.Duration {
+	d := 200 * time.Millisecond * time.Duration(1<<uint(attempt))
+
+	const maxBackoff = 5 * time.Second
+	if d > maxBackoff {
+		d = maxBackoff
+	}
+
+	return d
+}
+
+// isPreconditionFailed reports whether err is the 412 Precondition Failed Azure returns when an
+// IfMatch ETag no longer matches the current record set, i.e. someone else wrote to it first.
+func isPreconditionFailed(err error) bool {
+	var respErr *azcore.ResponseError
+	return errors.As(err, &respErr) && respErr.StatusCode == http.StatusPreconditionFailed
+}
+
+// retryRecordSetUpdate calls update, retrying with capped exponential backoff, for at most
+// maxRecordSetRetries attempts in all, while update keeps failing with a 412 Precondition Failed
+// (i.e. update is expected to re-fetch the record set and recompute its ETag-guarded write
+// on every call).
+func retryRecordSetUpdate(update func() error) error {
+	var lastErr error
+	for attempt := 0; attempt < maxRecordSetRetries; attempt++ {
+		if attempt > 0 {
+			time.Sleep(recordSetBackoff(attempt))
+		}
+
+		err := update()
+		if err == nil {
+			return nil
+		}
+
+		if !isPreconditionFailed(err) {
+			return err
+		}
+
+		lastErr = err
+	}
+
+	return lastErr
+}
@@ -28,3 +28,8 @@ func String(s *string) string {
 func Int64Ptr(i int64) *int64 {
 	return &i
 }
+
+// StringPtr returns a pointer to the passed string.
+func StringPtr(s string) *string {
+	return &s
+}
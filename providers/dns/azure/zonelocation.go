@@ -0,0 +1,147 @@
+package azure
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/resourcegraph/armresourcegraph"
+	"github.com/go-acme/lego/v4/challenge/dns01"
+	"github.com/go-acme/lego/v4/platform/config/env"
+)
+
+// ZoneRef identifies the subscription, resource group, and zone type (public or private)
+// that hosts a DNS zone, so a single DNSProvider can issue certificates against zones spread
+// across several subscriptions/resource groups.
+type ZoneRef struct {
+	SubscriptionID string
+	ResourceGroup  string
+	PrivateZone    bool
+}
+
+// getHostedZoneID resolves the zone name and the ZoneRef (subscription, resource group,
+// zone type) hosting it for fqdn.
+//
+// The resource group is resolved in order:
+//  1. config.ZoneToResourceGroup, keyed by the unFQDN zone name.
+//  2. the provider's default config.SubscriptionID/ResourceGroup/PrivateZone, when set.
+//  3. an Azure Resource Graph query over Microsoft.Network/dnszones and privateDnsZones,
+//     so users with no default resource group don't have to enumerate every zone by hand.
+func (d *dnsProvider) getHostedZoneID(ctx context.Context, fqdn string) (string, ZoneRef, error) {
+	zone := env.GetOrFile(EnvZoneName)
+	if zone == "" {
+		authZone, err := dns01.FindZoneByFqdn(fqdn)
+		if err != nil {
+			return "", ZoneRef{}, err
+		}
+		zone = dns01.UnFqdn(authZone)
+	}
+
+	ref, err := d.zoneLocation(ctx, zone)
+	if err != nil {
+		return "", ZoneRef{}, err
+	}
+
+	if ref.PrivateZone {
+		name, err := d.getHostedZoneIDPrivate(ctx, zone, ref)
+		return name, ref, err
+	}
+
+	name, err := d.getHostedZoneIDPublic(ctx, zone, ref)
+	return name, ref, err
+}
+
+// zoneLocation resolves the ZoneRef hosting the zone named zoneName (no trailing dot).
+//
+// An explicit config.SubscriptionID/ResourceGroup default always wins over auto-discovery: an
+// operator who configured one already knows where their zones live, so Resource Graph discovery
+// (a second round-trip the credential may not even have access to run, e.g. DNS Zone Contributor
+// without Microsoft.ResourceGraph access) is only attempted when there's no default to fall back
+// on in the first place.
+func (d *dnsProvider) zoneLocation(ctx context.Context, zoneName string) (ZoneRef, error) {
+	if ref, ok := d.config.ZoneToResourceGroup[zoneName]; ok {
+		return ref, nil
+	}
+
+	if d.config.SubscriptionID != "" && d.config.ResourceGroup != "" {
+		return ZoneRef{
+			SubscriptionID: d.config.SubscriptionID,
+			ResourceGroup:  d.config.ResourceGroup,
+			PrivateZone:    d.config.PrivateZone,
+		}, nil
+	}
+
+	ref, ok, err := d.discoverZoneLocation(ctx, zoneName)
+	if err != nil {
+		return ZoneRef{}, fmt.Errorf("azure: Resource Graph discovery failed for zone %q: %w", zoneName, err)
+	}
+	if !ok {
+		return ZoneRef{}, fmt.Errorf("azure: no resource group found for zone %q (not in ZoneToResourceGroup, not found via Resource Graph, and no default SubscriptionID/ResourceGroup configured)", zoneName)
+	}
+
+	return ref, nil
+}
+
+// discoverZoneLocation looks up the subscription and resource group containing the DNS zone
+// named zoneName via an Azure Resource Graph query. It searches config.SubscriptionID only,
+// or every subscription the credential can see when config.SubscriptionID is empty.
+//
+// The query unions public and private zones, so a tenant with both a public and a private zone
+// of the same name (the split-horizon setup PrivateZone exists to support) can match more than
+// one resource; ok/err alone can't disambiguate which one to use, so that case is reported as an
+// error rather than silently picking whichever row Resource Graph happened to return first.
+func (d *dnsProvider) discoverZoneLocation(ctx context.Context, zoneName string) (ZoneRef, bool, error) {
+	client, err := armresourcegraph.NewClient(d.authorizer, d.clientOptions())
+	if err != nil {
+		return ZoneRef{}, false, fmt.Errorf("armresourcegraph.NewClient: %w", err)
+	}
+
+	query := fmt.Sprintf(`Resources
+| where type in ('microsoft.network/dnszones', 'microsoft.network/privatednszones')
+| where name =~ '%s'
+| project subscriptionId, resourceGroup, type
+| order by subscriptionId asc, type asc`, zoneName)
+
+	resultFormat := armresourcegraph.ResultFormatObjectArray
+	request := armresourcegraph.QueryRequest{
+		Query: &query,
+		Options: &armresourcegraph.QueryRequestOptions{
+			ResultFormat: &resultFormat,
+		},
+	}
+	if d.config.SubscriptionID != "" {
+		request.Subscriptions = []*string{&d.config.SubscriptionID}
+	}
+
+	resp, err := client.Resources(ctx, request, nil)
+	if err != nil {
+		return ZoneRef{}, false, fmt.Errorf("armresourcegraph: %w", err)
+	}
+
+	rows, ok := resp.Data.([]interface{})
+	if !ok || len(rows) == 0 {
+		return ZoneRef{}, false, nil
+	}
+
+	if len(rows) > 1 {
+		return ZoneRef{}, false, fmt.Errorf("zone %q matched %d resources (e.g. both a public and a private zone of the same name); add it to ZoneToResourceGroup to disambiguate", zoneName, len(rows))
+	}
+
+	row, ok := rows[0].(map[string]interface{})
+	if !ok {
+		return ZoneRef{}, false, nil
+	}
+
+	subscriptionID, _ := row["subscriptionId"].(string)
+	resourceGroup, _ := row["resourceGroup"].(string)
+	resourceType, _ := row["type"].(string)
+
+	if subscriptionID == "" || resourceGroup == "" {
+		return ZoneRef{}, false, nil
+	}
+
+	return ZoneRef{
+		SubscriptionID: subscriptionID,
+		ResourceGroup:  resourceGroup,
+		PrivateZone:    resourceType == "microsoft.network/privatednszones",
+	}, true, nil
+}